@@ -0,0 +1,34 @@
+package vsock
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// An Addr is a vsock address.
+type Addr struct {
+	ContextID uint32
+	Port      uint32
+}
+
+// Network returns the address's network name, "vsock".
+func (a *Addr) Network() string { return "vsock" }
+
+// String returns the string representation of an Addr.
+func (a *Addr) String() string {
+	return fmt.Sprintf("vsock:%d:%d", a.ContextID, a.Port)
+}
+
+// sockaddrToVsock converts an unix.Sockaddr to an Addr, if possible.
+func sockaddrToVsock(sa unix.Sockaddr) (*Addr, error) {
+	vsa, ok := sa.(*unix.SockaddrVM)
+	if !ok {
+		return nil, fmt.Errorf("vsock: invalid address type: %T", sa)
+	}
+
+	return &Addr{
+		ContextID: vsa.CID,
+		Port:      vsa.Port,
+	}, nil
+}
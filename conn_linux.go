@@ -0,0 +1,75 @@
+package vsock
+
+import (
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+// A Conn is a vsock connection which implements net.Conn.
+type Conn struct {
+	fd     connFD
+	local  *Addr
+	remote *Addr
+}
+
+var _ net.Conn = &Conn{}
+
+// Dial dials a vsock connection to the given context ID and port. To
+// customize the dial with a context.Context or a Control hook, use a Dialer
+// instead.
+func Dial(cid, port uint32) (*Conn, error) {
+	return (&Dialer{}).Dial(cid, port)
+}
+
+// Read implements net.Conn.
+func (c *Conn) Read(b []byte) (int, error) { return c.fd.Read(b) }
+
+// Write implements net.Conn.
+func (c *Conn) Write(b []byte) (int, error) { return c.fd.Write(b) }
+
+// Close implements net.Conn.
+func (c *Conn) Close() error { return c.fd.Close() }
+
+// LocalAddr implements net.Conn.
+func (c *Conn) LocalAddr() net.Addr { return c.local }
+
+// RemoteAddr implements net.Conn.
+func (c *Conn) RemoteAddr() net.Addr { return c.remote }
+
+// SetDeadline implements net.Conn.
+func (c *Conn) SetDeadline(t time.Time) error { return c.fd.SetDeadline(t) }
+
+// SetReadDeadline implements net.Conn.
+func (c *Conn) SetReadDeadline(t time.Time) error { return c.fd.SetReadDeadline(t) }
+
+// SetWriteDeadline implements net.Conn.
+func (c *Conn) SetWriteDeadline(t time.Time) error { return c.fd.SetWriteDeadline(t) }
+
+// File returns a copy of the underlying os.File backing c. It is the
+// caller's responsibility to close f when finished. Closing c does not
+// affect f, and closing f does not affect c.
+//
+// As with net.UnixListener.File, holding f open while also calling Close on
+// c can be surprising: Close still interrupts any Read or Write blocked on
+// c, but the duplicated descriptor in f remains valid until f itself is
+// closed, since it refers to the same underlying socket.
+func (c *Conn) File() (*os.File, error) { return c.fd.File() }
+
+// SyscallConn returns a raw network connection, allowing a caller to set
+// VSOCK-specific socket options via syscall.RawConn.Control.
+func (c *Conn) SyscallConn() (syscall.RawConn, error) { return c.fd.SyscallConn() }
+
+// Buffers writes the contents of v to c, using a single writev(2) syscall
+// where possible instead of one Write call per buffer. This is unlike
+// net.Buffers.WriteTo, whose equivalent optimization only triggers for
+// io.Writers defined inside the net package, so callers that assemble a
+// header and payload as separate buffers (e.g. an RPC framing layer) should
+// call Buffers directly rather than relying on io.Copy/WriteTo to find it.
+func (c *Conn) Buffers(v net.Buffers) (int64, error) {
+	bs := make([][]byte, len(v))
+	copy(bs, v)
+
+	return c.fd.Writev(bs)
+}
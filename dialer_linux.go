@@ -0,0 +1,67 @@
+package vsock
+
+import (
+	"context"
+	"fmt"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// A Dialer dials vsock connections. It is the configurable equivalent of the
+// package-level Dial, modeled after net.Dialer.
+type Dialer struct {
+	// Control is called after the socket is created but before it is
+	// connected, allowing the caller to tune VSOCK-specific socket options
+	// such as SO_VM_SOCKETS_BUFFER_SIZE via c. Control may be nil.
+	Control func(network, address string, c syscall.RawConn) error
+
+	// NetNS, if non-zero, is a file descriptor referring to an entry under
+	// /proc/<pid>/ns/net. When set, the dialing socket is created inside
+	// that network namespace instead of the caller's own; see
+	// ListenConfig.NetNS.
+	NetNS int
+}
+
+// Dial behaves like the package-level Dial, but uses the options configured
+// on d.
+func (d *Dialer) Dial(cid, port uint32) (*Conn, error) {
+	return d.DialContext(context.Background(), cid, port)
+}
+
+// DialContext behaves like Dial, but aborts the dial as soon as ctx is done,
+// rather than waiting for connect(2) to time out or succeed on its own.
+func (d *Dialer) DialContext(ctx context.Context, cid, port uint32) (*Conn, error) {
+	cfd, err := newConnFD(d.NetNS)
+	if err != nil {
+		return nil, err
+	}
+
+	remote := &Addr{ContextID: cid, Port: port}
+
+	if err := controlFD(cfd.fd, "vsock", remote.String(), d.Control); err != nil {
+		_ = cfd.EarlyClose()
+		return nil, err
+	}
+
+	// On failure, ConnectContext closes cfd.f itself once cfd has
+	// transitioned to non-blocking mode, so there's no fd left for us to
+	// clean up here.
+	if err := cfd.ConnectContext(ctx, &unix.SockaddrVM{CID: cid, Port: port}, fmt.Sprintf("vsock:%d", port)); err != nil {
+		return nil, err
+	}
+
+	sa, err := cfd.Getsockname()
+	if err != nil {
+		_ = cfd.Close()
+		return nil, err
+	}
+
+	local, err := sockaddrToVsock(sa)
+	if err != nil {
+		_ = cfd.Close()
+		return nil, err
+	}
+
+	return &Conn{fd: cfd, local: local, remote: remote}, nil
+}
@@ -1,13 +1,111 @@
 package vsock
 
 import (
+	"context"
 	"io"
 	"os"
+	"runtime"
+	"syscall"
 	"time"
 
 	"golang.org/x/sys/unix"
 )
 
+// aLongTimeAgo is a non-zero time in the past, used to cancel a pending
+// read/write deadline without risking a zero-value time.Time being
+// interpreted as "no deadline" by *os.File.
+var aLongTimeAgo = time.Unix(1, 0)
+
+// dupCloseOnExec duplicates fd and marks the duplicate close-on-exec, so
+// that it isn't leaked across a subsequent exec. This mirrors the pattern
+// internal/poll.DupCloseOnExec uses to back net.Conn.File and
+// net.Listener.File.
+func dupCloseOnExec(fd int) (int, error) {
+	nfd, err := unix.Dup(fd)
+	if err != nil {
+		return 0, err
+	}
+
+	unix.CloseOnExec(nfd)
+	return nfd, nil
+}
+
+// withNetNS runs fn with the calling goroutine locked to an OS thread that
+// has entered the network namespace referenced by the netns file descriptor
+// (an entry in /proc/<pid>/ns/net), restoring the thread's original
+// namespace before returning. If netns is 0, fn runs as-is in the caller's
+// current namespace.
+func withNetNS(netns int, fn func() (int, error)) (int, error) {
+	if netns == 0 {
+		return fn()
+	}
+
+	// setns(2) only affects the calling thread, so we must pin this
+	// goroutine to its current OS thread for the duration of the namespace
+	// switch. Unlike most uses of LockOSThread, we do not unconditionally
+	// defer UnlockOSThread: if we can't prove the thread made it back to
+	// its original namespace, unlocking it would return a thread to the
+	// scheduler that silently performs syscalls in the wrong namespace for
+	// whatever goroutine the scheduler reuses it for next.
+	runtime.LockOSThread()
+
+	origNS, err := unix.Open("/proc/thread-self/ns/net", unix.O_RDONLY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		runtime.UnlockOSThread()
+		return 0, err
+	}
+	defer unix.Close(origNS)
+
+	if err := unix.Setns(netns, unix.CLONE_NEWNET); err != nil {
+		runtime.UnlockOSThread()
+		return 0, err
+	}
+
+	fd, fnErr := fn()
+
+	if nerr := unix.Setns(origNS, unix.CLONE_NEWNET); nerr != nil {
+		// We're stuck in netns with no way to confirm we can get back, so
+		// this thread must never be returned to the scheduler. Sacrifice it
+		// by exiting this goroutine without unlocking, the same workaround
+		// vishvananda/netns uses for this exact failure.
+		runtime.Goexit()
+	}
+
+	runtime.UnlockOSThread()
+	return fd, fnErr
+}
+
+// controlFD invokes ctrl, if non-nil, against fd before it is bound or
+// connected, giving a Dialer or ListenConfig Control hook a chance to set
+// VSOCK-specific socket options. fd is still in blocking mode at this point,
+// but syscall.RawConn.Control only issues a syscall against the descriptor
+// and does not require non-blocking I/O.
+func controlFD(fd int, network, address string, ctrl func(network, address string, c syscall.RawConn) error) error {
+	if ctrl == nil {
+		return nil
+	}
+
+	// Wrap a duplicate of fd just long enough to hand out a
+	// syscall.RawConn. os.NewFile installs a finalizer that closes the
+	// descriptor it wraps when the file is garbage collected, so wrapping fd
+	// itself would let the real socket get closed out from under the caller
+	// at some unpredictable later GC cycle. Socket options set through the
+	// duplicate apply to the same socket, and closing it leaves fd open.
+	nfd, err := dupCloseOnExec(fd)
+	if err != nil {
+		return err
+	}
+	f := os.NewFile(uintptr(nfd), "vsock-control")
+	defer f.Close()
+
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	return ctrl(network, address, rc)
+}
+
 // A listenFD is a type that wraps a file descriptor used to implement
 // net.Listener.
 type listenFD interface {
@@ -18,6 +116,8 @@ type listenFD interface {
 	Listen(n int) error
 	Getsockname() (unix.Sockaddr, error)
 	SetNonblocking(name string) error
+	SyscallConn() (syscall.RawConn, error)
+	File() (*os.File, error)
 }
 
 var _ listenFD = &sysListenFD{}
@@ -29,9 +129,13 @@ type sysListenFD struct {
 	f  *os.File // Used in non-blocking mode.
 }
 
-// newListenFD creates a sysListenFD in its default blocking mode.
-func newListenFD() (*sysListenFD, error) {
-	fd, err := unix.Socket(unix.AF_VSOCK, unix.SOCK_STREAM, 0)
+// newListenFD creates a sysListenFD in its default blocking mode. If netns is
+// non-zero, the socket is created inside the network namespace it refers to;
+// see withNetNS.
+func newListenFD(netns int) (*sysListenFD, error) {
+	fd, err := withNetNS(netns, func() (int, error) {
+		return unix.Socket(unix.AF_VSOCK, unix.SOCK_STREAM, 0)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -116,23 +220,62 @@ func (lfd *sysListenFD) Close() error {
 	return lfd.f.Close()
 }
 
+// SyscallConn returns a raw network connection wrapping the non-blocking
+// *os.File, backing Listener.SyscallConn and Listener.SetOptions. A
+// ListenConfig.Control hook runs earlier, against the blocking fd, through
+// controlFD instead.
+func (lfd *sysListenFD) SyscallConn() (syscall.RawConn, error) { return lfd.f.SyscallConn() }
+
+// File returns a dup of the underlying file descriptor. It does not call
+// lfd.f.Fd, so it does not disturb lfd's non-blocking registration with the
+// runtime poller: Close on lfd still interrupts a pending Accept4 even after
+// File has been called.
+func (lfd *sysListenFD) File() (*os.File, error) {
+	rc, err := lfd.f.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		nfd  int
+		derr error
+	)
+	if cerr := rc.Control(func(fd uintptr) {
+		nfd, derr = dupCloseOnExec(int(fd))
+	}); cerr != nil {
+		return nil, cerr
+	}
+	if derr != nil {
+		return nil, derr
+	}
+
+	return os.NewFile(uintptr(nfd), lfd.f.Name()), nil
+}
+
 // A connFD is a type that wraps a file descriptor used to implement net.Conn.
 type connFD interface {
 	io.ReadWriteCloser
 	EarlyClose() error
-	Connect(sa unix.Sockaddr) error
+	ConnectContext(ctx context.Context, sa unix.Sockaddr, name string) error
 	Getsockname() (unix.Sockaddr, error)
 	SetNonblocking(name string) error
 	SetDeadline(t time.Time) error
 	SetReadDeadline(t time.Time) error
 	SetWriteDeadline(t time.Time) error
+	SyscallConn() (syscall.RawConn, error)
+	File() (*os.File, error)
+	Writev(bs [][]byte) (int64, error)
 }
 
 var _ connFD = &sysConnFD{}
 
-// newConnFD creates a sysConnFD in its default blocking mode.
-func newConnFD() (*sysConnFD, error) {
-	fd, err := unix.Socket(unix.AF_VSOCK, unix.SOCK_STREAM, 0)
+// newConnFD creates a sysConnFD in its default blocking mode. If netns is
+// non-zero, the socket is created inside the network namespace it refers to;
+// see withNetNS.
+func newConnFD(netns int) (*sysConnFD, error) {
+	fd, err := withNetNS(netns, func() (int, error) {
+		return unix.Socket(unix.AF_VSOCK, unix.SOCK_STREAM, 0)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -151,13 +294,95 @@ type sysConnFD struct {
 
 // Blocking mode methods.
 
-func (cfd *sysConnFD) Connect(sa unix.Sockaddr) error      { return unix.Connect(cfd.fd, sa) }
 func (cfd *sysConnFD) Getsockname() (unix.Sockaddr, error) { return unix.Getsockname(cfd.fd) }
 
 // EarlyClose is a blocking version of Close, only used for cleanup before
 // entering non-blocking mode.
 func (cfd *sysConnFD) EarlyClose() error { return unix.Close(cfd.fd) }
 
+// ConnectContext transitions cfd to non-blocking mode and then connects to
+// sa, mirroring the blocking-to-non-blocking transition performed by
+// SetNonblocking. Connecting in non-blocking mode lets us abort a pending
+// connect(2) when ctx is done, by waking the poller with aLongTimeAgo rather
+// than blocking until the kernel gives up, which mirrors how netFD.connect
+// cancels a dial in the standard library.
+//
+// Once the transition above succeeds, cfd.fd is owned by cfd.f: any error
+// returned from this point on closes cfd.f (via the deferred cleanup below)
+// rather than leaving the caller to unix.Close(cfd.fd) directly, which would
+// race the finalizer cfd.f still has armed on that same descriptor number.
+func (cfd *sysConnFD) ConnectContext(ctx context.Context, sa unix.Sockaddr, name string) (err error) {
+	if err := unix.SetNonblock(cfd.fd, true); err != nil {
+		return err
+	}
+	cfd.f = os.NewFile(uintptr(cfd.fd), name)
+
+	defer func() {
+		if err != nil {
+			_ = cfd.f.Close()
+		}
+	}()
+
+	switch cerr := unix.Connect(cfd.fd, sa); cerr {
+	case nil, unix.EISCONN:
+		return nil
+	case unix.EINPROGRESS, unix.EALREADY, unix.EAGAIN:
+		// The connection is in progress; wait for the socket to become
+		// writable below.
+	default:
+		return cerr
+	}
+
+	rc, err := cfd.f.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	if done := ctx.Done(); done != nil {
+		stop := make(chan struct{})
+		defer close(stop)
+
+		go func() {
+			select {
+			case <-done:
+				// Force the pending Write below to return immediately so we
+				// can report ctx's error instead of blocking forever.
+				_ = cfd.f.SetWriteDeadline(aLongTimeAgo)
+			case <-stop:
+			}
+		}()
+	}
+
+	var soErr int
+	werr := rc.Write(func(fd uintptr) bool {
+		soErr, err = unix.GetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_ERROR)
+		if err != nil || soErr != 0 {
+			return true
+		}
+
+		// SO_ERROR stays 0 while the connect is still in progress, so the
+		// dial is only done once the socket has a peer; until then, return
+		// false to let the poller wait for writability.
+		_, perr := unix.Getpeername(int(fd))
+		return perr == nil
+	})
+	if werr != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return werr
+	}
+	if err != nil {
+		return err
+	}
+	if soErr != 0 {
+		return unix.Errno(soErr)
+	}
+
+	// Clear the temporary write deadline installed above, if any.
+	return cfd.f.SetWriteDeadline(time.Time{})
+}
+
 func (cfd *sysConnFD) SetNonblocking(name string) error {
 	// From now on, we must perform non-blocking I/O, so that our deadline
 	// methods work, and the connection can be interrupted by net.Conn.Close.
@@ -195,3 +420,207 @@ func (cfd *sysConnFD) Write(b []byte) (int, error)        { return cfd.f.Write(b
 func (cfd *sysConnFD) SetDeadline(t time.Time) error      { return cfd.f.SetDeadline(t) }
 func (cfd *sysConnFD) SetReadDeadline(t time.Time) error  { return cfd.f.SetReadDeadline(t) }
 func (cfd *sysConnFD) SetWriteDeadline(t time.Time) error { return cfd.f.SetWriteDeadline(t) }
+
+// SyscallConn returns a raw network connection wrapping the non-blocking
+// *os.File, backing Conn.SyscallConn, Conn.SetOptions and the splice paths
+// of Conn.ReadFrom/WriteTo. A Dialer.Control hook runs earlier, against the
+// blocking fd, through controlFD instead.
+func (cfd *sysConnFD) SyscallConn() (syscall.RawConn, error) { return cfd.f.SyscallConn() }
+
+// File returns a dup of the underlying file descriptor. It does not call
+// cfd.f.Fd, so it does not disturb cfd's non-blocking registration with the
+// runtime poller: Close on cfd still interrupts a pending Read/Write even
+// after File has been called.
+func (cfd *sysConnFD) File() (*os.File, error) {
+	rc, err := cfd.f.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		nfd  int
+		derr error
+	)
+	if cerr := rc.Control(func(fd uintptr) {
+		nfd, derr = dupCloseOnExec(int(fd))
+	}); cerr != nil {
+		return nil, cerr
+	}
+	if derr != nil {
+		return nil, derr
+	}
+
+	return os.NewFile(uintptr(nfd), cfd.f.Name()), nil
+}
+
+// Writev writes bs to cfd using as few writev(2) syscalls as possible,
+// looping to handle short writes and waiting for writability through the
+// runtime poller on EAGAIN, the same pattern RecvFrom/SendTo use for
+// datagram sockets.
+func (cfd *sysConnFD) Writev(bs [][]byte) (int64, error) {
+	rc, err := cfd.f.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for len(bs) > 0 {
+		var (
+			n    int
+			werr error
+		)
+
+		doErr := rc.Write(func(fd uintptr) bool {
+			n, werr = unix.Writev(int(fd), bs)
+
+			return werr != unix.EAGAIN
+		})
+		if doErr != nil {
+			return total, doErr
+		}
+		if werr != nil {
+			return total, werr
+		}
+
+		total += int64(n)
+		bs = trimBuffers(bs, n)
+	}
+
+	return total, nil
+}
+
+// trimBuffers drops the first n written bytes from bs, returning the
+// remaining, possibly partially-consumed, buffers left to write.
+func trimBuffers(bs [][]byte, n int) [][]byte {
+	for len(bs) > 0 {
+		if n < len(bs[0]) {
+			bs[0] = bs[0][n:]
+			return bs
+		}
+
+		n -= len(bs[0])
+		bs = bs[1:]
+	}
+
+	return bs
+}
+
+// A packetFD is a type that wraps a file descriptor used to implement
+// net.PacketConn for a SOCK_DGRAM vsock socket.
+type packetFD interface {
+	io.Closer
+	EarlyClose() error
+	Bind(sa unix.Sockaddr) error
+	Getsockname() (unix.Sockaddr, error)
+	SetNonblocking(name string) error
+	SetDeadline(t time.Time) error
+	SetReadDeadline(t time.Time) error
+	SetWriteDeadline(t time.Time) error
+	RecvFrom(b []byte) (int, unix.Sockaddr, error)
+	SendTo(b []byte, sa unix.Sockaddr) (int, error)
+}
+
+var _ packetFD = &sysPacketFD{}
+
+// A sysPacketFD is the system call implementation of packetFD.
+type sysPacketFD struct {
+	// These fields should never be non-zero at the same time.
+	fd int      // Used in blocking mode.
+	f  *os.File // Used in non-blocking mode.
+}
+
+// newPacketFD creates a sysPacketFD in its default blocking mode.
+func newPacketFD() (*sysPacketFD, error) {
+	fd, err := unix.Socket(unix.AF_VSOCK, unix.SOCK_DGRAM, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sysPacketFD{
+		fd: fd,
+	}, nil
+}
+
+// Blocking mode methods.
+
+func (pfd *sysPacketFD) Bind(sa unix.Sockaddr) error         { return unix.Bind(pfd.fd, sa) }
+func (pfd *sysPacketFD) Getsockname() (unix.Sockaddr, error) { return unix.Getsockname(pfd.fd) }
+
+// EarlyClose is a blocking version of Close, only used for cleanup before
+// entering non-blocking mode.
+func (pfd *sysPacketFD) EarlyClose() error { return unix.Close(pfd.fd) }
+
+func (pfd *sysPacketFD) SetNonblocking(name string) error {
+	// From now on, we must perform non-blocking I/O, so that our deadline
+	// methods work and the connection can be interrupted by Close, just as
+	// with sysConnFD.
+	if err := unix.SetNonblock(pfd.fd, true); err != nil {
+		return err
+	}
+
+	// Transition from blocking mode to non-blocking mode.
+	pfd.f = os.NewFile(uintptr(pfd.fd), name)
+
+	return nil
+}
+
+// Non-blocking mode methods.
+
+func (pfd *sysPacketFD) Close() error {
+	// *os.File.Close will also close the runtime network poller file descriptor,
+	// so that Recvfrom/Sendto can stop blocking.
+	return pfd.f.Close()
+}
+
+func (pfd *sysPacketFD) SetDeadline(t time.Time) error      { return pfd.f.SetDeadline(t) }
+func (pfd *sysPacketFD) SetReadDeadline(t time.Time) error  { return pfd.f.SetReadDeadline(t) }
+func (pfd *sysPacketFD) SetWriteDeadline(t time.Time) error { return pfd.f.SetWriteDeadline(t) }
+
+func (pfd *sysPacketFD) RecvFrom(b []byte) (int, unix.Sockaddr, error) {
+	rc, err := pfd.f.SyscallConn()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var (
+		n    int
+		sa   unix.Sockaddr
+		rerr error
+	)
+
+	doErr := rc.Read(func(fd uintptr) bool {
+		n, sa, rerr = unix.Recvfrom(int(fd), b, 0)
+
+		// Return false to let the poller wait for readiness on EAGAIN, the
+		// same pattern used by Accept4 above.
+		return rerr != unix.EAGAIN
+	})
+	if doErr != nil {
+		return 0, nil, doErr
+	}
+
+	return n, sa, rerr
+}
+
+func (pfd *sysPacketFD) SendTo(b []byte, sa unix.Sockaddr) (int, error) {
+	rc, err := pfd.f.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+
+	var serr error
+
+	doErr := rc.Write(func(fd uintptr) bool {
+		serr = unix.Sendto(int(fd), b, 0, sa)
+
+		return serr != unix.EAGAIN
+	})
+	if doErr != nil {
+		return 0, doErr
+	}
+	if serr != nil {
+		return 0, serr
+	}
+
+	return len(b), nil
+}
@@ -0,0 +1,36 @@
+package vsock
+
+import "syscall"
+
+// A ListenConfig contains options for listening on a vsock address. It is
+// the configurable equivalent of the package-level Listen, modeled after
+// net.ListenConfig.
+type ListenConfig struct {
+	// Control is called after the socket is created but before it is bound,
+	// allowing the caller to tune VSOCK-specific socket options such as
+	// SO_VM_SOCKETS_BUFFER_SIZE via c. Control may be nil.
+	Control func(network, address string, c syscall.RawConn) error
+
+	// NetNS, if non-zero, is a file descriptor referring to an entry under
+	// /proc/<pid>/ns/net. When set, the listening socket is created inside
+	// that network namespace instead of the caller's own, which lets a
+	// supervisor on the host reach guest VSOCK endpoints that are only
+	// bound from within a particular container's namespace.
+	NetNS int
+}
+
+// Listen behaves like the package-level Listen, but uses the options
+// configured on lc.
+func (lc *ListenConfig) Listen(cid, port uint32) (*Listener, error) {
+	lfd, err := newListenFD(lc.NetNS)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := controlFD(lfd.fd, "vsock", (&Addr{ContextID: cid, Port: port}).String(), lc.Control); err != nil {
+		_ = lfd.EarlyClose()
+		return nil, err
+	}
+
+	return listen(lfd, cid, port)
+}
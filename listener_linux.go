@@ -0,0 +1,96 @@
+package vsock
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// A Listener is a vsock network listener which implements net.Listener.
+type Listener struct {
+	fd   listenFD
+	addr *Addr
+}
+
+var _ net.Listener = &Listener{}
+
+// Listen opens a vsock listener bound to the given context ID and port. Use
+// unix.VMADDR_CID_ANY to bind to all context IDs, or unix.VMADDR_CID_HOST to
+// bind to the host when called from within a guest. To customize the
+// listener with a Control hook, use a ListenConfig instead.
+func Listen(cid, port uint32) (*Listener, error) {
+	return (&ListenConfig{}).Listen(cid, port)
+}
+
+func listen(lfd listenFD, cid, port uint32) (*Listener, error) {
+	if err := lfd.Bind(&unix.SockaddrVM{CID: cid, Port: port}); err != nil {
+		_ = lfd.EarlyClose()
+		return nil, err
+	}
+
+	if err := lfd.Listen(unix.SOMAXCONN); err != nil {
+		_ = lfd.EarlyClose()
+		return nil, err
+	}
+
+	sa, err := lfd.Getsockname()
+	if err != nil {
+		_ = lfd.EarlyClose()
+		return nil, err
+	}
+
+	addr, err := sockaddrToVsock(sa)
+	if err != nil {
+		_ = lfd.EarlyClose()
+		return nil, err
+	}
+
+	if err := lfd.SetNonblocking(fmt.Sprintf("vsock-listen:%d", addr.Port)); err != nil {
+		return nil, err
+	}
+
+	return &Listener{fd: lfd, addr: addr}, nil
+}
+
+// Accept implements net.Listener.
+func (l *Listener) Accept() (net.Conn, error) {
+	cfd, sa, err := l.fd.Accept4(0)
+	if err != nil {
+		return nil, err
+	}
+
+	remote, err := sockaddrToVsock(sa)
+	if err != nil {
+		_ = cfd.EarlyClose()
+		return nil, err
+	}
+
+	if err := cfd.SetNonblocking(fmt.Sprintf("vsock-conn:%d", remote.Port)); err != nil {
+		return nil, err
+	}
+
+	return &Conn{fd: cfd, local: l.addr, remote: remote}, nil
+}
+
+// Close implements net.Listener.
+func (l *Listener) Close() error { return l.fd.Close() }
+
+// Addr implements net.Listener.
+func (l *Listener) Addr() net.Addr { return l.addr }
+
+// File returns a copy of the underlying os.File backing l. It is the
+// caller's responsibility to close f when finished. Closing l does not
+// affect f, and closing f does not affect l.
+//
+// As with net.UnixListener.File, holding f open while also calling Close on
+// l can be surprising: Close still interrupts any pending Accept on l, but
+// the duplicated descriptor in f remains valid until f itself is closed,
+// since it refers to the same underlying socket.
+func (l *Listener) File() (*os.File, error) { return l.fd.File() }
+
+// SyscallConn returns a raw network connection, allowing a caller to set
+// VSOCK-specific socket options via syscall.RawConn.Control.
+func (l *Listener) SyscallConn() (syscall.RawConn, error) { return l.fd.SyscallConn() }
@@ -0,0 +1,189 @@
+package vsock
+
+import (
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// SOL_VSOCK isn't exposed by golang.org/x/sys/unix; its value is mirrored
+// here from the kernel's include/uapi/linux/vm_sockets.h. The SO_VM_SOCKETS_*
+// option constants themselves are unix.SO_VM_SOCKETS_*.
+const solVsock = 287
+
+// ConnOptions holds VSOCK-specific (SOL_VSOCK) socket options that tune the
+// virtio-vsock ring buffers and connection behavior of a Conn or Listener.
+//
+// For the buffer size and timeout fields, a zero value means "leave this
+// option unchanged", since 0 is not a meaningful buffer size or timeout.
+// Trusted has no such unset value, so SetOptions always applies it.
+type ConnOptions struct {
+	// BufferSize sets SO_VM_SOCKETS_BUFFER_SIZE, the size in bytes of the
+	// socket's receive buffer.
+	BufferSize uint64
+
+	// MinBufferSize sets SO_VM_SOCKETS_BUFFER_MIN_SIZE, the smallest
+	// receive buffer size the peer may negotiate down to.
+	MinBufferSize uint64
+
+	// MaxBufferSize sets SO_VM_SOCKETS_BUFFER_MAX_SIZE, the largest receive
+	// buffer size the peer may negotiate up to.
+	MaxBufferSize uint64
+
+	// ConnectTimeout sets SO_VM_SOCKETS_CONNECT_TIMEOUT, how long a
+	// subsequent connect(2) on the socket may take before failing.
+	ConnectTimeout time.Duration
+
+	// Trusted sets SO_VM_SOCKETS_TRUSTED, marking the socket as trusted so
+	// it bypasses VSOCK's transport-level access controls. Setting this
+	// requires CAP_NET_ADMIN.
+	Trusted bool
+
+	// Nonblock, if non-nil, sets the socket's O_NONBLOCK file status flag
+	// directly to *Nonblock. Leave this nil unless you specifically need
+	// to change non-blocking mode: every Conn and Listener this package
+	// hands out is already non-blocking internally so that SetDeadline and
+	// Close-based cancellation of a pending Accept/Read/Write work, and
+	// setting *Nonblock = false turns that off for the life of the fd.
+	Nonblock *bool
+}
+
+// SetOptions applies opts to c.
+func (c *Conn) SetOptions(opts ConnOptions) error {
+	rc, err := c.fd.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	return setConnOptions(rc, opts)
+}
+
+// GetOptions reads back c's current SOL_VSOCK options and O_NONBLOCK state.
+func (c *Conn) GetOptions() (ConnOptions, error) {
+	rc, err := c.fd.SyscallConn()
+	if err != nil {
+		return ConnOptions{}, err
+	}
+
+	return getConnOptions(rc)
+}
+
+// SetOptions applies opts to l. Because the buffer size and timeout options
+// only take effect before a connection is established, set them before the
+// first call to Accept.
+func (l *Listener) SetOptions(opts ConnOptions) error {
+	rc, err := l.fd.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	return setConnOptions(rc, opts)
+}
+
+// GetOptions reads back l's current SOL_VSOCK options and O_NONBLOCK state.
+func (l *Listener) GetOptions() (ConnOptions, error) {
+	rc, err := l.fd.SyscallConn()
+	if err != nil {
+		return ConnOptions{}, err
+	}
+
+	return getConnOptions(rc)
+}
+
+func setConnOptions(rc syscall.RawConn, opts ConnOptions) error {
+	var serr error
+
+	cerr := rc.Control(func(fd uintptr) {
+		if opts.BufferSize != 0 {
+			if serr = unix.SetsockoptUint64(int(fd), solVsock, unix.SO_VM_SOCKETS_BUFFER_SIZE, opts.BufferSize); serr != nil {
+				return
+			}
+		}
+		if opts.MinBufferSize != 0 {
+			if serr = unix.SetsockoptUint64(int(fd), solVsock, unix.SO_VM_SOCKETS_BUFFER_MIN_SIZE, opts.MinBufferSize); serr != nil {
+				return
+			}
+		}
+		if opts.MaxBufferSize != 0 {
+			if serr = unix.SetsockoptUint64(int(fd), solVsock, unix.SO_VM_SOCKETS_BUFFER_MAX_SIZE, opts.MaxBufferSize); serr != nil {
+				return
+			}
+		}
+		if opts.ConnectTimeout != 0 {
+			tv := unix.NsecToTimeval(opts.ConnectTimeout.Nanoseconds())
+			if serr = unix.SetsockoptTimeval(int(fd), solVsock, unix.SO_VM_SOCKETS_CONNECT_TIMEOUT, &tv); serr != nil {
+				return
+			}
+		}
+
+		if serr = setsockoptBool(int(fd), solVsock, unix.SO_VM_SOCKETS_TRUSTED, opts.Trusted); serr != nil {
+			return
+		}
+
+		if opts.Nonblock != nil {
+			serr = unix.SetNonblock(int(fd), *opts.Nonblock)
+		}
+	})
+	if cerr != nil {
+		return cerr
+	}
+
+	return serr
+}
+
+func getConnOptions(rc syscall.RawConn) (ConnOptions, error) {
+	var (
+		opts ConnOptions
+		serr error
+	)
+
+	cerr := rc.Control(func(fd uintptr) {
+		if opts.BufferSize, serr = unix.GetsockoptUint64(int(fd), solVsock, unix.SO_VM_SOCKETS_BUFFER_SIZE); serr != nil {
+			return
+		}
+		if opts.MinBufferSize, serr = unix.GetsockoptUint64(int(fd), solVsock, unix.SO_VM_SOCKETS_BUFFER_MIN_SIZE); serr != nil {
+			return
+		}
+		if opts.MaxBufferSize, serr = unix.GetsockoptUint64(int(fd), solVsock, unix.SO_VM_SOCKETS_BUFFER_MAX_SIZE); serr != nil {
+			return
+		}
+
+		var trusted int
+		if trusted, serr = unix.GetsockoptInt(int(fd), solVsock, unix.SO_VM_SOCKETS_TRUSTED); serr != nil {
+			return
+		}
+		opts.Trusted = trusted != 0
+
+		var tv *unix.Timeval
+		if tv, serr = unix.GetsockoptTimeval(int(fd), solVsock, unix.SO_VM_SOCKETS_CONNECT_TIMEOUT); serr != nil {
+			return
+		}
+		opts.ConnectTimeout = time.Duration(unix.TimevalToNsec(*tv))
+
+		var flags int
+		if flags, serr = unix.FcntlInt(fd, unix.F_GETFL, 0); serr != nil {
+			return
+		}
+		nonblock := flags&unix.O_NONBLOCK != 0
+		opts.Nonblock = &nonblock
+	})
+	if cerr != nil {
+		return ConnOptions{}, cerr
+	}
+	if serr != nil {
+		return ConnOptions{}, serr
+	}
+
+	return opts, nil
+}
+
+// setsockoptBool sets or clears a boolean SOL_VSOCK option.
+func setsockoptBool(fd, level, opt int, v bool) error {
+	var i int
+	if v {
+		i = 1
+	}
+
+	return unix.SetsockoptInt(fd, level, opt, i)
+}
@@ -0,0 +1,94 @@
+package vsock
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// A PacketConn is a connectionless, packet-oriented vsock socket which
+// implements net.PacketConn.
+type PacketConn struct {
+	fd    packetFD
+	local *Addr
+}
+
+var _ net.PacketConn = &PacketConn{}
+
+// ListenPacket opens a SOCK_DGRAM vsock socket bound to the given context ID
+// and port and returns a net.PacketConn.
+func ListenPacket(cid, port uint32) (*PacketConn, error) {
+	pfd, err := newPacketFD()
+	if err != nil {
+		return nil, err
+	}
+
+	return listenPacket(pfd, cid, port)
+}
+
+func listenPacket(pfd packetFD, cid, port uint32) (*PacketConn, error) {
+	if err := pfd.Bind(&unix.SockaddrVM{CID: cid, Port: port}); err != nil {
+		_ = pfd.EarlyClose()
+		return nil, err
+	}
+
+	sa, err := pfd.Getsockname()
+	if err != nil {
+		_ = pfd.EarlyClose()
+		return nil, err
+	}
+
+	local, err := sockaddrToVsock(sa)
+	if err != nil {
+		_ = pfd.EarlyClose()
+		return nil, err
+	}
+
+	if err := pfd.SetNonblocking(fmt.Sprintf("vsock-packet:%d", local.Port)); err != nil {
+		return nil, err
+	}
+
+	return &PacketConn{fd: pfd, local: local}, nil
+}
+
+// ReadFrom implements net.PacketConn.
+func (c *PacketConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	n, sa, err := c.fd.RecvFrom(b)
+	if err != nil {
+		return n, nil, err
+	}
+
+	addr, err := sockaddrToVsock(sa)
+	if err != nil {
+		return n, nil, err
+	}
+
+	return n, addr, nil
+}
+
+// WriteTo implements net.PacketConn.
+func (c *PacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	a, ok := addr.(*Addr)
+	if !ok {
+		return 0, fmt.Errorf("vsock: invalid address type: %T", addr)
+	}
+
+	return c.fd.SendTo(b, &unix.SockaddrVM{CID: a.ContextID, Port: a.Port})
+}
+
+// Close implements net.PacketConn.
+func (c *PacketConn) Close() error { return c.fd.Close() }
+
+// LocalAddr implements net.PacketConn.
+func (c *PacketConn) LocalAddr() net.Addr { return c.local }
+
+// SetDeadline implements net.PacketConn.
+func (c *PacketConn) SetDeadline(t time.Time) error { return c.fd.SetDeadline(t) }
+
+// SetReadDeadline implements net.PacketConn.
+func (c *PacketConn) SetReadDeadline(t time.Time) error { return c.fd.SetReadDeadline(t) }
+
+// SetWriteDeadline implements net.PacketConn.
+func (c *PacketConn) SetWriteDeadline(t time.Time) error { return c.fd.SetWriteDeadline(t) }
@@ -0,0 +1,194 @@
+package vsock
+
+import (
+	"io"
+	"net"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// maxSpliceSize is the largest chunk moved by a single splice(2) call.
+const maxSpliceSize = 4 << 20
+
+// spliceRawConn returns the syscall.RawConn backing v, if v is a type this
+// package knows how to splice to or from: a TCP or Unix socket, another
+// vsock.Conn, or a file descriptor such as a pipe.
+func spliceRawConn(v interface{}) (syscall.RawConn, bool) {
+	switch t := v.(type) {
+	case *net.TCPConn:
+		return rawConnOrNil(t.SyscallConn())
+	case *net.UnixConn:
+		return rawConnOrNil(t.SyscallConn())
+	case *Conn:
+		return rawConnOrNil(t.SyscallConn())
+	case *os.File:
+		return rawConnOrNil(t.SyscallConn())
+	default:
+		return nil, false
+	}
+}
+
+func rawConnOrNil(rc syscall.RawConn, err error) (syscall.RawConn, bool) {
+	if err != nil {
+		return nil, false
+	}
+
+	return rc, true
+}
+
+// ReadFrom implements io.ReaderFrom. When r is backed by a TCP or Unix
+// socket, another vsock.Conn, or a pipe, ReadFrom moves data from r to c
+// using splice(2) through an intermediate pipe, avoiding a userspace copy.
+// This is useful for proxying workloads that tunnel bytes between a VSOCK
+// socket and a TCP/Unix socket. For any other r, ReadFrom falls back to
+// io.Copy.
+func (c *Conn) ReadFrom(r io.Reader) (int64, error) {
+	src, ok := spliceRawConn(r)
+	if !ok {
+		return io.Copy(writerOnly{c}, r)
+	}
+
+	dst, err := c.fd.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+
+	n, handled, err := splice(dst, src)
+	if !handled {
+		return io.Copy(writerOnly{c}, r)
+	}
+
+	return n, err
+}
+
+// WriteTo implements io.WriterTo, the mirror image of ReadFrom: when w is
+// backed by a TCP or Unix socket, another vsock.Conn, or a pipe, WriteTo
+// moves data from c to w using splice(2). This makes io.Copy(dst, conn)
+// pick up the zero-copy path automatically, the same way io.Copy already
+// special-cases os.File and net.TCPConn. For any other w, WriteTo falls
+// back to io.Copy.
+func (c *Conn) WriteTo(w io.Writer) (int64, error) {
+	dst, ok := spliceRawConn(w)
+	if !ok {
+		return io.Copy(w, readerOnly{c})
+	}
+
+	src, err := c.fd.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+
+	n, handled, err := splice(dst, src)
+	if !handled {
+		return io.Copy(w, readerOnly{c})
+	}
+
+	return n, err
+}
+
+// writerOnly hides any ReaderFrom method c may have, so that the io.Copy
+// fallback above can't recurse back into ReadFrom.
+type writerOnly struct{ io.Writer }
+
+// readerOnly hides any WriterTo method c may have, so that the io.Copy
+// fallback above can't recurse back into WriteTo.
+type readerOnly struct{ io.Reader }
+
+// splicePipe is the intermediate pipe splice(2) copies through, since Linux
+// requires one end of every splice to be a pipe.
+type splicePipe struct {
+	rfd int
+	wfd int
+}
+
+func newSplicePipe() (*splicePipe, error) {
+	var fds [2]int
+	if err := unix.Pipe2(fds[:], unix.O_CLOEXEC|unix.O_NONBLOCK); err != nil {
+		return nil, err
+	}
+
+	return &splicePipe{rfd: fds[0], wfd: fds[1]}, nil
+}
+
+func (p *splicePipe) Close() {
+	_ = unix.Close(p.rfd)
+	_ = unix.Close(p.wfd)
+}
+
+// splice moves data from src to dst via an intermediate pipe until src
+// reaches EOF or an error occurs, waiting for readiness through the poller
+// on EAGAIN the same way RecvFrom/SendTo do. handled reports whether
+// splice(2) could be used at all; when handled is false, no bytes have been
+// moved and the caller should fall back to a userspace copy instead.
+func splice(dst, src syscall.RawConn) (n int64, handled bool, err error) {
+	pipe, perr := newSplicePipe()
+	if perr != nil {
+		return 0, false, nil
+	}
+	defer pipe.Close()
+
+	for {
+		var (
+			nread   int64
+			readErr error
+		)
+
+		rerr := src.Read(func(fd uintptr) bool {
+			nread, readErr = unix.Splice(int(fd), nil, pipe.wfd, nil, maxSpliceSize, unix.SPLICE_F_MOVE|unix.SPLICE_F_NONBLOCK)
+			return readErr != unix.EAGAIN
+		})
+		if rerr != nil {
+			return n, handled, rerr
+		}
+
+		switch readErr {
+		case nil:
+			// Fall through to drain the pipe into dst below.
+		case unix.EINVAL, unix.ENOSYS:
+			// splice(2) isn't usable for this fd pair. If we haven't moved
+			// any bytes yet, tell the caller to fall back to a userspace
+			// copy instead.
+			if n == 0 {
+				return 0, false, nil
+			}
+			return n, true, readErr
+		default:
+			return n, true, readErr
+		}
+
+		handled = true
+
+		if nread == 0 {
+			// src reached EOF.
+			return n, true, nil
+		}
+
+		// Drain exactly nread bytes out of the pipe into dst. splice(2)
+		// never puts more into the pipe than we asked it to read, so this
+		// loop is bounded.
+		var written int64
+		for written < nread {
+			var (
+				nwrote   int64
+				writeErr error
+			)
+
+			werr := dst.Write(func(fd uintptr) bool {
+				nwrote, writeErr = unix.Splice(pipe.rfd, nil, int(fd), nil, int(nread-written), unix.SPLICE_F_MOVE|unix.SPLICE_F_NONBLOCK)
+				return writeErr != unix.EAGAIN
+			})
+			if werr != nil {
+				return n + written, true, werr
+			}
+			if writeErr != nil {
+				return n + written, true, writeErr
+			}
+
+			written += nwrote
+		}
+
+		n += written
+	}
+}